@@ -6,89 +6,74 @@ import (
 	"log"
 	"net"
 	"os"
+	"os/signal"
 	"slices"
 	"strconv"
-	"sync"
+	"syscall"
 	"talos-probe/probe"
+	"talos-probe/reconcile"
 	"talos-probe/talos"
 	"time"
 
 	"github.com/thedevsaddam/unpack"
 )
 
-func dialTalosHosts(ips []string, batchSize int) ([]string, error) {
-	if batchSize <= 0 && batchSize != -1 {
-		log.Fatal("batch size cannot be less than or equal to 0")
-	}
-
-	if batchSize == -1 {
-		batchSize = 100
-	}
+const defaultProbeInterval = 60 * time.Second
 
-	talosHosts := make([]string, 0)
-	var mu sync.Mutex
-
-	fmt.Printf("dialing %d talos hosts with batch size %d...\n", len(ips), batchSize)
-
-	for i := 0; i < len(ips); i += batchSize {
-		end := i + batchSize
-		if end > len(ips) {
-			end = len(ips)
-		}
-
-		batch := ips[i:end]
-		wg := sync.WaitGroup{}
-		wg.Add(len(batch))
-
-		fmt.Printf("processing batch %d-%d of %d\n", i+1, end, len(ips))
-
-		for _, ip := range batch {
-			go func(ip string) {
-				defer wg.Done()
-				err := probe.DialAddress(fmt.Sprintf("%s:50000", ip), 3*time.Second)
-				if err != nil {
-					return
-				}
-				log.Printf("connected to address %s successfully", ip)
-				mu.Lock()
-				talosHosts = append(talosHosts, ip)
-				mu.Unlock()
-			}(ip)
-		}
-
-		wg.Wait()
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "watch" {
+		runWatch(os.Args[2:])
+		return
 	}
 
-	return talosHosts, nil
+	runScan(os.Args[1:])
 }
 
-func main() {
-	if len(os.Args) < 5 {
-		fmt.Println("Usage: talos_scanner <cidr> <talosconfig path> <worker config path> <batch size>")
+// runScan performs a single scan-and-join pass over the CIDR.
+func runScan(args []string) {
+	if len(args) < 4 {
+		fmt.Println("Usage: talos_scanner <cidr> <talosconfig path> <config path> <concurrency> [worker|controlplane] [--bootstrap]")
 		return
 	}
 
-	var cidr, talosConfigPath, workerConfigPath, batchSize string
-	unpack.Do(os.Args[1:], &cidr, &talosConfigPath, &workerConfigPath, &batchSize)
+	var cidr, talosConfigPath, configPath, concurrency string
+	unpack.Do(args[:4], &cidr, &talosConfigPath, &configPath, &concurrency)
 	log.Println("cidr:", cidr)
 	log.Println("talosConfigPath:", talosConfigPath)
-	log.Println("workerConfigPath:", workerConfigPath)
+	log.Println("configPath:", configPath)
 
-	ips, err := probe.IpsFromCIDR(cidr)
-	if err != nil {
-		log.Fatalf("cidr parse error: %v", err)
+	role := talos.RoleWorker
+	if len(args) > 4 && args[4] != "--bootstrap" {
+		var err error
+		role, err = talos.ParseRole(args[4])
+		if err != nil {
+			log.Fatalf("role parse error: %v", err)
+		}
 	}
 
-	log.Printf("Total IPs to scan: %d\n", len(ips))
+	bootstrapFirst := slices.Contains(args, "--bootstrap")
+
+	paths := talos.ConfigPaths{}
+	switch role {
+	case talos.RoleWorker:
+		paths.WorkerConfigPath = configPath
+	case talos.RoleControlPlane:
+		paths.ControlPlaneConfigPath = configPath
+	}
 
-	batchSizeInt, err := strconv.Atoi(batchSize)
+	concurrencyInt, err := strconv.Atoi(concurrency)
 	if err != nil {
-		log.Fatalf("batch size parse error: %v", err)
+		log.Fatalf("concurrency parse error: %v", err)
 	}
 
-	talosHostsInCidr, err := dialTalosHosts(ips, batchSizeInt)
+	candidates, err := probe.ScanCIDR(context.TODO(), cidr, concurrencyInt)
 	if err != nil {
-		log.Println("faild to dial talos hosts.")
+		log.Fatalf("cidr scan error: %v", err)
+	}
+
+	talosHostsInCidr := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		talosHostsInCidr = append(talosHostsInCidr, candidate.IP)
 	}
 	fmt.Println("talos hosts:", talosHostsInCidr)
 
@@ -120,16 +105,15 @@ func main() {
 			continue
 		}
 
-		// Validate IP format
-		ip := net.ParseIP(talosHost)
-		if ip == nil || ip.To4() == nil {
+		// Validate IP format (any family - IPv6 candidates are expected too)
+		if net.ParseIP(talosHost) == nil {
 			continue
 		}
 
 		// Check if this host is already a member
 		isExistingMember := false
 		for _, member := range members {
-			if member.InternalIP == talosHost {
+			if slices.Contains(member.Addresses, talosHost) {
 				isExistingMember = true
 				break
 			}
@@ -148,10 +132,77 @@ func main() {
 	}
 
 	for _, ip := range newMemberIps {
-		log.Printf("joining worker: %s\n", ip)
-		err := talos.WorkerJoin(context.TODO(), ip, workerConfigPath, talosConfigPath, 10*time.Second)
+		log.Printf("joining %s: %s\n", role, ip)
+		if err := talos.Join(context.TODO(), ip, role, paths, 10*time.Second); err != nil {
+			log.Println("failed to join:", err)
+			continue
+		}
+
+		if bootstrapFirst && role == talos.RoleControlPlane {
+			log.Printf("bootstrapping etcd on %s (may take several minutes while it reboots)\n", ip)
+			if err := talos.Bootstrap(context.TODO(), ip, talosConfigPath, 10*time.Second); err != nil {
+				log.Println("failed to bootstrap:", err)
+			}
+			bootstrapFirst = false
+		}
+	}
+}
+
+// runWatch starts the reconciler daemon, probing the CIDR on an interval and
+// joining newly discovered hosts until it's interrupted.
+func runWatch(args []string) {
+	if len(args) < 4 {
+		fmt.Println("Usage: talos_scanner watch <cidr> <talosconfig path> <config path> <concurrency> [worker|controlplane] [probe interval seconds]")
+		return
+	}
+
+	var cidr, talosConfigPath, configPath, concurrency string
+	unpack.Do(args[:4], &cidr, &talosConfigPath, &configPath, &concurrency)
+
+	concurrencyInt, err := strconv.Atoi(concurrency)
+	if err != nil {
+		log.Fatalf("concurrency parse error: %v", err)
+	}
+
+	role := talos.RoleWorker
+	if len(args) > 4 {
+		role, err = talos.ParseRole(args[4])
+		if err != nil {
+			log.Fatalf("role parse error: %v", err)
+		}
+	}
+
+	paths := talos.ConfigPaths{}
+	switch role {
+	case talos.RoleWorker:
+		paths.WorkerConfigPath = configPath
+	case talos.RoleControlPlane:
+		paths.ControlPlaneConfigPath = configPath
+	}
+
+	interval := defaultProbeInterval
+	if len(args) > 5 {
+		seconds, err := strconv.Atoi(args[5])
 		if err != nil {
-			log.Println("failed to join worker:", err)
+			log.Fatalf("probe interval parse error: %v", err)
 		}
+		interval = time.Duration(seconds) * time.Second
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("watching %s every %s, joining new hosts as %s\n", cidr, interval, role)
+
+	err = reconcile.Run(ctx, reconcile.Options{
+		CIDR:            cidr,
+		TalosConfigPath: talosConfigPath,
+		Role:            role,
+		ConfigPaths:     paths,
+		Concurrency:     concurrencyInt,
+		ProbeInterval:   interval,
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Fatalf("reconciler exited: %v", err)
 	}
 }
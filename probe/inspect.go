@@ -0,0 +1,82 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	"github.com/siderolabs/talos/pkg/machinery/resources/hardware"
+	"github.com/siderolabs/talos/pkg/machinery/resources/network"
+	"github.com/siderolabs/talos/pkg/machinery/resources/runtime"
+)
+
+// NodeInfo describes a host discovered on the network that responds to the
+// Talos maintenance-mode gRPC API.
+type NodeInfo struct {
+	IP           string
+	Stage        runtime.MachineStage
+	SerialNumber string
+	MACAddresses []string
+	LinkNames    []string
+}
+
+// IsMaintenanceCandidate reports whether the node is a fresh, unjoined Talos
+// host sitting in maintenance mode and is therefore safe to join.
+func (n *NodeInfo) IsMaintenanceCandidate() bool {
+	return n.Stage == runtime.MachineStageMaintenance
+}
+
+// Inspect opens an insecure maintenance-mode client against ip and reads the
+// non-sensitive resources needed to classify the host: its boot stage plus,
+// for maintenance-mode candidates, hardware serial number and network link
+// information.
+func Inspect(ctx context.Context, ip string, timeout time.Duration) (*NodeInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	c, err := client.New(ctx,
+		client.WithTLSConfig(&tls.Config{InsecureSkipVerify: true}), //nolint:gosec
+		client.WithEndpoints(net.JoinHostPort(ip, "50000")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial maintenance client: %w", err)
+	}
+	defer c.Close() //nolint:errcheck
+
+	machineStatus, err := safe.StateGetByID[*runtime.MachineStatus](ctx, c.COSI, runtime.MachineStatusID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get machine status: %w", err)
+	}
+
+	info := &NodeInfo{
+		IP:    ip,
+		Stage: machineStatus.TypedSpec().Stage,
+	}
+
+	if !info.IsMaintenanceCandidate() {
+		return info, nil
+	}
+
+	if sysInfo, err := safe.StateGetByID[*hardware.SystemInformation](ctx, c.COSI, hardware.SystemInformationID); err == nil {
+		info.SerialNumber = sysInfo.TypedSpec().SerialNumber
+	}
+
+	links, err := safe.StateListAll[*network.LinkStatus](ctx, c.COSI)
+	if err != nil {
+		return info, fmt.Errorf("failed to list link status: %w", err)
+	}
+
+	for link := range links.All() {
+		info.LinkNames = append(info.LinkNames, link.Metadata().ID())
+
+		if mac := link.TypedSpec().HardwareAddr.String(); mac != "" {
+			info.MACAddresses = append(info.MACAddresses, mac)
+		}
+	}
+
+	return info, nil
+}
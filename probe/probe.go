@@ -1,51 +1,70 @@
 package probe
 
 import (
-	"log"
-	"net"
+	"fmt"
+	"iter"
+	"net/netip"
 	"strings"
-	"time"
 )
 
-// IpsFromCIDR: returns []string of IPs (excludes network and broadcast for v4)
-func IpsFromCidrs(cidrs string) ([]string, error) {
-	cidrsList := strings.Split(cidrs, ",")
-	var allIps []string
-	for _, cidr := range cidrsList {
-		ips := make([]string, 0)
-		_, ipnet, err := net.ParseCIDR(cidr)
+// IpsFromCidrs parses a comma-separated list of CIDRs and returns an iterator
+// over every usable address in them, without materializing the full address
+// list up front. IPv4 network and broadcast addresses are skipped; IPv6
+// all-zero (subnet-router anycast) addresses are skipped except on /127 and
+// /128 prefixes, where every address is usable per RFC 6164.
+func IpsFromCidrs(cidrs string) (iter.Seq[netip.Addr], error) {
+	var prefixes []netip.Prefix
+	for _, cidr := range strings.Split(cidrs, ",") {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("cidr parse error: %w", err)
 		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
 
-		for ip := ipnet.IP.Mask(ipnet.Mask); ipnet.Contains(ip); nextIP(ip) {
-			ips = append(ips, ip.String())
-		}
-		// remove network and broadcast if IPv4 and length>2
-		if len(ips) > 2 && ipnet.IP.To4() != nil {
-			allIps = append(allIps, ips[1:len(ips)-1]...)
-		} else {
-			allIps = append(allIps, ips...)
+	return func(yield func(netip.Addr) bool) {
+		for _, prefix := range prefixes {
+			for ip := prefix.Addr(); prefix.Contains(ip); ip = ip.Next() {
+				if shouldSkip(ip, prefix) {
+					continue
+				}
+				if !yield(ip) {
+					return
+				}
+			}
 		}
-	}
-	return allIps, nil
+	}, nil
 }
 
-func nextIP(ip net.IP) {
-	for j := len(ip) - 1; j >= 0; j-- {
-		ip[j]++
-		if ip[j] != 0 {
-			break
+// shouldSkip reports whether ip is a reserved address that should not be
+// probed: the network or broadcast address of a v4 prefix, or the
+// subnet-router anycast address of a v6 prefix.
+func shouldSkip(ip netip.Addr, prefix netip.Prefix) bool {
+	if ip.Is4() {
+		bits := prefix.Bits()
+		if bits >= 31 {
+			return false
 		}
+		return ip == prefix.Addr() || ip == lastAddr(prefix)
 	}
+
+	bits := prefix.Bits()
+	if bits >= 127 {
+		return false
+	}
+	return ip == prefix.Addr()
 }
 
-func DialAddress(addr string, timeout time.Duration) error {
-	conn, err := net.DialTimeout("tcp", addr, timeout)
-	if err != nil {
-		return err
+// lastAddr returns the highest address in prefix (the broadcast address for
+// a v4 prefix).
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	bits := prefix.Bits()
+
+	for i := bits; i < len(bytes)*8; i++ {
+		bytes[i/8] |= 1 << (7 - i%8)
 	}
-	defer conn.Close()
-	log.Printf("connected to address %s successfully", addr)
-	return nil
+
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
 }
@@ -0,0 +1,65 @@
+package probe
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestShouldSkip(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		ip     string
+		want   bool
+	}{
+		{"v4 /24 network address", "10.0.0.0/24", "10.0.0.0", true},
+		{"v4 /24 broadcast address", "10.0.0.0/24", "10.0.0.255", true},
+		{"v4 /24 first usable address", "10.0.0.0/24", "10.0.0.1", false},
+		{"v4 /24 last usable address", "10.0.0.0/24", "10.0.0.254", false},
+		{"v4 /31 low address is usable", "10.0.0.0/31", "10.0.0.0", false},
+		{"v4 /31 high address is usable", "10.0.0.0/31", "10.0.0.1", false},
+		{"v4 /32 sole address is usable", "10.0.0.5/32", "10.0.0.5", false},
+		{"v6 /64 subnet-router anycast address", "2001:db8::/64", "2001:db8::", true},
+		{"v6 /64 ordinary address", "2001:db8::/64", "2001:db8::1", false},
+		{"v6 /127 low address is usable", "2001:db8::/127", "2001:db8::", false},
+		{"v6 /127 high address is usable", "2001:db8::/127", "2001:db8::1", false},
+		{"v6 /128 sole address is usable", "2001:db8::1/128", "2001:db8::1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix := netip.MustParsePrefix(tt.prefix).Masked()
+			ip := netip.MustParseAddr(tt.ip)
+
+			if got := shouldSkip(ip, prefix); got != tt.want {
+				t.Errorf("shouldSkip(%s, %s) = %v, want %v", ip, prefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastAddr(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		want   string
+	}{
+		{"v4 /24", "10.0.0.0/24", "10.0.0.255"},
+		{"v4 /31", "10.0.0.0/31", "10.0.0.1"},
+		{"v4 /32", "10.0.0.5/32", "10.0.0.5"},
+		{"v6 /64", "2001:db8::/64", "2001:db8::ffff:ffff:ffff:ffff"},
+		{"v6 /127", "2001:db8::/127", "2001:db8::1"},
+		{"v6 /128", "2001:db8::1/128", "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix := netip.MustParsePrefix(tt.prefix).Masked()
+			want := netip.MustParseAddr(tt.want)
+
+			if got := lastAddr(prefix); got != want {
+				t.Errorf("lastAddr(%s) = %s, want %s", prefix, got, want)
+			}
+		})
+	}
+}
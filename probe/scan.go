@@ -0,0 +1,69 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// ScanCIDR streams every address in cidr and probes up to concurrency of them
+// at once for the Talos maintenance-mode API, returning the hosts found to be
+// fresh, unjoined maintenance-mode candidates. Scanning stops early if ctx is
+// canceled.
+func ScanCIDR(ctx context.Context, cidr string, concurrency int) ([]*NodeInfo, error) {
+	if concurrency <= 0 && concurrency != -1 {
+		return nil, fmt.Errorf("concurrency cannot be less than or equal to 0")
+	}
+
+	if concurrency == -1 {
+		concurrency = 100
+	}
+
+	ips, err := IpsFromCidrs(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("cidr parse error: %w", err)
+	}
+
+	var (
+		sem        = semaphore.NewWeighted(int64(concurrency))
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		candidates = make([]*NodeInfo, 0)
+	)
+
+	log.Printf("dialing talos hosts on %s with up to %d concurrent probes...\n", cidr, concurrency)
+
+	for ip := range ips {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(ip string) {
+			defer wg.Done()
+			defer sem.Release(1)
+
+			info, err := Inspect(ctx, ip, 3*time.Second)
+			if err != nil {
+				return
+			}
+			if !info.IsMaintenanceCandidate() {
+				log.Printf("address %s is a talos host but not in maintenance mode (stage=%s), skipping", ip, info.Stage)
+				return
+			}
+
+			log.Printf("found maintenance-mode talos host %s", ip)
+			mu.Lock()
+			candidates = append(candidates, info)
+			mu.Unlock()
+		}(ip.String())
+	}
+
+	wg.Wait()
+
+	return candidates, nil
+}
@@ -0,0 +1,210 @@
+// Package reconcile implements a small reconciler daemon: desired state is
+// "every reachable Talos host on the CIDR should be a cluster member",
+// current state is the watched cluster.Member set, and a probe loop diffs
+// the two and joins whatever is missing.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/cosi-project/runtime/pkg/state"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	"github.com/siderolabs/talos/pkg/machinery/resources/cluster"
+
+	"talos-probe/probe"
+	"talos-probe/talos"
+)
+
+const (
+	// restartBackoff is how long to wait before restarting a failed loop.
+	restartBackoff = 5 * time.Second
+
+	// defaultJoinTimeout is used when Options.JoinTimeout is unset.
+	defaultJoinTimeout = 10 * time.Second
+)
+
+// Options configures the reconciler daemon.
+type Options struct {
+	CIDR            string
+	TalosConfigPath string
+	Role            talos.Role
+	ConfigPaths     talos.ConfigPaths
+	Concurrency     int
+	ProbeInterval   time.Duration
+	JoinTimeout     time.Duration
+}
+
+// Run starts the reconciler and blocks until ctx is canceled. A loop that
+// returns an error is restarted by supervise rather than ending Run.
+func Run(ctx context.Context, opts Options) error {
+	if opts.JoinTimeout == 0 {
+		opts.JoinTimeout = defaultJoinTimeout
+	}
+
+	r := &reconciler{
+		opts:      opts,
+		members:   newMemberSet(),
+		cooldowns: newCooldownTracker(),
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error { return supervise(ctx, "member watch", r.watchMembers) })
+	g.Go(func() error { return supervise(ctx, "cidr probe", r.probeLoop) })
+
+	return g.Wait()
+}
+
+// supervise runs fn until ctx is done, restarting it after restartBackoff
+// whenever it returns a non-nil error, so the watch loop and the probe loop
+// can fail independently without taking each other down.
+func supervise(ctx context.Context, name string, fn func(context.Context) error) error {
+	for {
+		err := fn(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil {
+			return nil
+		}
+
+		log.Printf("%s loop failed, restarting in %s: %v", name, restartBackoff, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(restartBackoff):
+		}
+	}
+}
+
+type reconciler struct {
+	opts      Options
+	members   *memberSet
+	cooldowns *cooldownTracker
+}
+
+// watchMembers watches cluster.Member resources and keeps r.members in sync
+// with the current cluster membership in real time.
+func (r *reconciler) watchMembers(ctx context.Context) error {
+	c, err := client.New(ctx, client.WithConfigFromFile(r.opts.TalosConfigPath))
+	if err != nil {
+		return fmt.Errorf("failed to create talos client: %w", err)
+	}
+	defer c.Close() //nolint:errcheck
+
+	eventCh := make(chan safe.WrappedStateEvent[*cluster.Member])
+	kind := cluster.NewMember(cluster.NamespaceName, "").Metadata()
+
+	if err := safe.StateWatchKind[*cluster.Member](ctx, c.COSI, kind, eventCh, state.WithBootstrapContents(true)); err != nil {
+		return fmt.Errorf("failed to watch cluster members: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-eventCh:
+			if err := r.handleMemberEvent(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (r *reconciler) handleMemberEvent(event safe.WrappedStateEvent[*cluster.Member]) error {
+	if event.Type() == state.Errored {
+		return event.Error()
+	}
+
+	member, err := event.Resource()
+	if err != nil {
+		return nil
+	}
+
+	ip := internalIP(member)
+	if ip == "" {
+		return nil
+	}
+
+	if event.Type() == state.Destroyed {
+		r.members.Remove(ip)
+		return nil
+	}
+
+	r.members.Add(ip)
+	r.cooldowns.Clear(ip)
+
+	return nil
+}
+
+// probeLoop runs the CIDR probe on opts.ProbeInterval and reconciles
+// whatever it finds against the current member set.
+func (r *reconciler) probeLoop(ctx context.Context) error {
+	ticker := time.NewTicker(r.opts.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.reconcileOnce(ctx); err != nil {
+			log.Printf("reconcile pass failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcileOnce scans the CIDR and joins every maintenance-mode candidate
+// that isn't already a cluster member and isn't in its join cooldown.
+func (r *reconciler) reconcileOnce(ctx context.Context) error {
+	candidates, err := probe.ScanCIDR(ctx, r.opts.CIDR, r.opts.Concurrency)
+	if err != nil {
+		return fmt.Errorf("failed to scan cidr: %w", err)
+	}
+
+	for _, candidate := range candidates {
+		ip := candidate.IP
+
+		if r.members.Has(ip) || !r.cooldowns.Ready(ip) {
+			continue
+		}
+
+		log.Printf("joining %s: %s\n", r.opts.Role, ip)
+
+		if err := talos.Join(ctx, ip, r.opts.Role, r.opts.ConfigPaths, r.opts.JoinTimeout); err != nil {
+			log.Printf("failed to join %s %s: %v", r.opts.Role, ip, err)
+			r.cooldowns.Fail(ip)
+			continue
+		}
+
+		r.cooldowns.Clear(ip)
+	}
+
+	return nil
+}
+
+// internalIP returns the first IPv4 address of a cluster member, falling
+// back to the first address of any family.
+func internalIP(member *cluster.Member) string {
+	addresses := member.TypedSpec().Addresses
+	for _, addr := range addresses {
+		if addr.Is4() {
+			return addr.String()
+		}
+	}
+
+	if len(addresses) > 0 {
+		return addresses[0].String()
+	}
+
+	return ""
+}
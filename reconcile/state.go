@@ -0,0 +1,91 @@
+package reconcile
+
+import (
+	"sync"
+	"time"
+)
+
+// memberSet is a concurrency-safe set of cluster member internal IPs.
+type memberSet struct {
+	mu  sync.RWMutex
+	ips map[string]struct{}
+}
+
+func newMemberSet() *memberSet {
+	return &memberSet{ips: make(map[string]struct{})}
+}
+
+func (s *memberSet) Add(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ips[ip] = struct{}{}
+}
+
+func (s *memberSet) Remove(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.ips, ip)
+}
+
+func (s *memberSet) Has(ip string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.ips[ip]
+	return ok
+}
+
+const (
+	minJoinBackoff = 10 * time.Second
+	maxJoinBackoff = 5 * time.Minute
+)
+
+// cooldownTracker enforces exponential backoff and a per-IP cooldown between
+// join attempts, so a broken node isn't retried on every reconcile pass.
+type cooldownTracker struct {
+	mu      sync.Mutex
+	nextTry map[string]time.Time
+	backoff map[string]time.Duration
+}
+
+func newCooldownTracker() *cooldownTracker {
+	return &cooldownTracker{
+		nextTry: make(map[string]time.Time),
+		backoff: make(map[string]time.Duration),
+	}
+}
+
+// Ready reports whether ip is past its cooldown and can be retried.
+func (t *cooldownTracker) Ready(ip string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	next, ok := t.nextTry[ip]
+	return !ok || !time.Now().Before(next)
+}
+
+// Fail records a join failure for ip, doubling its backoff up to maxJoinBackoff.
+func (t *cooldownTracker) Fail(ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	backoff := t.backoff[ip] * 2
+	if backoff < minJoinBackoff {
+		backoff = minJoinBackoff
+	}
+	if backoff > maxJoinBackoff {
+		backoff = maxJoinBackoff
+	}
+
+	t.backoff[ip] = backoff
+	t.nextTry[ip] = time.Now().Add(backoff)
+}
+
+// Clear resets ip's backoff, e.g. after a successful join or once it shows up
+// as a cluster member.
+func (t *cooldownTracker) Clear(ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.backoff, ip)
+	delete(t.nextTry, ip)
+}
@@ -0,0 +1,67 @@
+package talos
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/siderolabs/talos/pkg/machinery/config/configloader"
+	"github.com/siderolabs/talos/pkg/machinery/config/types/v1alpha1"
+)
+
+// configPatch customizes per-target fields applied to a loaded machine
+// config document set before it is sent to a node.
+type configPatch struct {
+	Hostname    string
+	InstallDisk string
+}
+
+// loadMachineConfig reads path, which may contain a single v1alpha1 document
+// or a multi-document Talos machine config (v1alpha1 config,
+// SideroLinkConfig, ExtensionServiceConfig, etc., separated by `---`),
+// applies patch to the v1alpha1 document if set, and re-serializes the
+// combined document set.
+func loadMachineConfig(path string, patch configPatch) ([]byte, error) {
+	provider, err := configloader.NewFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load machine config %q: %w", path, err)
+	}
+
+	if patch.Hostname != "" || patch.InstallDisk != "" {
+		provider, err = provider.PatchV1Alpha1(func(cfg *v1alpha1.Config) error {
+			if patch.Hostname != "" {
+				if cfg.MachineConfig.MachineNetwork == nil {
+					cfg.MachineConfig.MachineNetwork = &v1alpha1.NetworkConfig{}
+				}
+				cfg.MachineConfig.MachineNetwork.NetworkHostname = patch.Hostname
+			}
+
+			if patch.InstallDisk != "" {
+				if cfg.MachineConfig.MachineInstall == nil {
+					cfg.MachineConfig.MachineInstall = &v1alpha1.InstallConfig{}
+				}
+				cfg.MachineConfig.MachineInstall.InstallDisk = patch.InstallDisk
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to patch machine config %q: %w", path, err)
+		}
+	}
+
+	return provider.Bytes()
+}
+
+// reverseDNSHostname resolves ip's PTR record for use as a hostname patch,
+// bounded by ctx. It returns "" if ip has no PTR record, which is common in
+// freshly provisioned networks, or if ctx is done before the lookup returns.
+func reverseDNSHostname(ctx context.Context, ip string) string {
+	names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+
+	return strings.TrimSuffix(names[0], ".")
+}
@@ -2,15 +2,16 @@ package talos
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"fmt"
 	"net"
-	"os"
-	"os/exec"
-	"strings"
 	"time"
 
-	"github.com/thedevsaddam/unpack"
+	"github.com/cosi-project/runtime/pkg/safe"
+	"github.com/siderolabs/talos/pkg/machinery/api/machine"
+	"github.com/siderolabs/talos/pkg/machinery/client"
+	"github.com/siderolabs/talos/pkg/machinery/resources/block"
+	"github.com/siderolabs/talos/pkg/machinery/resources/cluster"
 )
 
 // Member represents a Talos cluster member
@@ -35,89 +36,56 @@ func (member *Member) IsWorker() bool {
 	return member.MachineType == "worker"
 }
 
-// GetMembers fetches and parses Talos cluster members using talosctl
+// GetMembers fetches the cluster.Member resources from the cluster namespace
+// using the Talos machinery gRPC client.
 // talosConfigPath: path to talosconfig file (e.g., "./talosconfig")
-// timeout: command execution timeout
+// timeout: how long to wait for the client to connect and list members
 func GetMembers(ctx context.Context, talosConfigPath string, timeout time.Duration) ([]Member, error) {
-	// Create context with timeout
-	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// Build talosctl command
-	cmd := exec.CommandContext(cmdCtx, "talosctl", "get", "members", "-o", "json")
-
-	// Set environment with TALOSCONFIG - inherit parent environment first
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, fmt.Sprintf("TALOSCONFIG=%s", talosConfigPath))
-
-	// Execute command and capture output
-	output, err := cmd.CombinedOutput()
+	c, err := client.New(ctx, client.WithConfigFromFile(talosConfigPath))
 	if err != nil {
-		if cmdCtx.Err() == context.DeadlineExceeded {
-			return nil, fmt.Errorf("command timed out after %v", timeout)
-		}
-		return nil, fmt.Errorf("failed to execute talosctl: %w, output: %s", err, string(output))
+		return nil, fmt.Errorf("failed to create talos client: %w", err)
 	}
+	defer c.Close() //nolint:errcheck
 
-	// Parse JSON output
-	members, err := parseMembers(output)
+	list, err := safe.StateListAll[*cluster.Member](ctx, c.COSI)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse members: %w", err)
+		return nil, fmt.Errorf("failed to list cluster members: %w", err)
+	}
+
+	members := make([]Member, 0, list.Len())
+	for item := range list.All() {
+		members = append(members, memberFromResource(item))
 	}
 
 	return members, nil
 }
 
-// parseMembers parses the JSON output from talosctl get members
-func parseMembers(data []byte) ([]Member, error) {
-	type MemberResponse struct {
-		Metadata struct {
-			ID        string `json:"id"`
-			Namespace string `json:"namespace"`
-			Type      string `json:"type"`
-			Version   int    `json:"version"`
-		} `json:"metadata"`
-		Node string `json:"node"`
-		Spec struct {
-			NodeID          string   `json:"nodeId"`
-			Addresses       []string `json:"addresses"`
-			Hostname        string   `json:"hostname"`
-			MachineType     string   `json:"machineType"`
-			OperatingSystem string   `json:"operatingSystem"`
-		} `json:"spec"`
-	}
-
-	// The output is multiple JSON objects separated by newlines
-	// We need to parse each complete JSON object
-	dataStr := strings.TrimSpace(string(data))
-	var members []Member
-
-	// Use a JSON decoder to handle multiple JSON objects
-	decoder := json.NewDecoder(strings.NewReader(dataStr))
-
-	for decoder.More() {
-		var result MemberResponse
-		if err := decoder.Decode(&result); err != nil {
-			return nil, fmt.Errorf("failed to parse JSON object: %w", err)
-		}
+// memberFromResource converts a cluster.Member COSI resource into the Member
+// shape used by the rest of this package.
+func memberFromResource(res *cluster.Member) Member {
+	md := res.Metadata()
+	spec := res.TypedSpec()
 
-		member := Member{
-			Node:        result.Node,
-			Namespace:   result.Metadata.Namespace,
-			Type:        result.Metadata.Type,
-			ID:          result.Metadata.ID,
-			Version:     fmt.Sprintf("%d", result.Metadata.Version),
-			Hostname:    result.Spec.Hostname,
-			MachineType: result.Spec.MachineType,
-			OS:          result.Spec.OperatingSystem,
-			Addresses:   result.Spec.Addresses,
-			InternalIP:  extractInternalIP(result.Spec.Addresses),
-		}
-
-		members = append(members, member)
+	addresses := make([]string, 0, len(spec.Addresses))
+	for _, addr := range spec.Addresses {
+		addresses = append(addresses, addr.String())
 	}
 
-	return members, nil
+	return Member{
+		Node:        md.ID(),
+		Namespace:   md.Namespace(),
+		Type:        md.Type(),
+		ID:          md.ID(),
+		Version:     md.Version().String(),
+		Hostname:    spec.Hostname,
+		MachineType: spec.MachineType.String(),
+		OS:          spec.OperatingSystem,
+		Addresses:   addresses,
+		InternalIP:  extractInternalIP(addresses),
+	}
 }
 
 // extractInternalIP extracts the first IPv4 address from the addresses list
@@ -135,76 +103,133 @@ func extractInternalIP(addresses []string) string {
 	return ""
 }
 
-// parseMembersTable parses the table format output from talosctl get members
-func parseMembersTable(data string) ([]Member, error) {
-	lines := strings.Split(strings.TrimSpace(data), "\n")
-	if len(lines) < 2 {
-		return nil, fmt.Errorf("invalid output: expected at least 2 lines")
+// Join applies the machine config for role to a node still in maintenance
+// mode, joining it to the cluster. The config file is looked up in paths by
+// role, then patched with the node's reverse-DNS hostname (if any) and an
+// install disk chosen from the node's own maintenance-mode block.Disk
+// resources (if a suitable one is found, it replaces any install disk set in
+// the config).
+func Join(ctx context.Context, ip string, role Role, paths ConfigPaths, timeout time.Duration) error {
+	configPath, err := paths.forRole(role)
+	if err != nil {
+		return err
 	}
 
-	// Skip header line
-	var members []Member
-	for i := 1; i < len(lines); i++ {
-		line := strings.TrimSpace(lines[i])
-		if line == "" {
-			continue
-		}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-		// Parse each field (space-separated, but addresses are in JSON array format)
-		fields := strings.Fields(line)
-		if len(fields) < 9 {
-			continue
-		}
+	c, err := client.New(ctx,
+		client.WithTLSConfig(&tls.Config{InsecureSkipVerify: true}), //nolint:gosec
+		client.WithEndpoints(net.JoinHostPort(ip, "50000")),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create maintenance client: %w", err)
+	}
+	defer c.Close() //nolint:errcheck
+
+	data, err := loadMachineConfig(configPath, configPatch{
+		Hostname:    reverseDNSHostname(ctx, ip),
+		InstallDisk: selectInstallDisk(ctx, c),
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.MachineClient.ApplyConfiguration(ctx, &machine.ApplyConfigurationRequest{Data: data}); err != nil {
+		return fmt.Errorf("failed to join %s as %s: %w", ip, role, err)
+	}
+
+	return nil
+}
 
-		// Extract addresses from JSON array format
-		addressesStart := strings.Index(line, "[")
-		addressesEnd := strings.Index(line, "]")
-		var addresses []string
-		if addressesStart != -1 && addressesEnd != -1 {
-			addressesStr := line[addressesStart+1 : addressesEnd]
-			addressesParts := strings.Split(addressesStr, ",")
-			for _, addr := range addressesParts {
-				addresses = append(addresses, strings.Trim(addr, `" `))
-			}
+// WorkerJoin is a convenience wrapper around Join for the common case of
+// joining a single-role worker cluster.
+func WorkerJoin(ctx context.Context, ip string, workerConfigPath string, timeout time.Duration) error {
+	return Join(ctx, ip, RoleWorker, ConfigPaths{WorkerConfigPath: workerConfigPath}, timeout)
+}
+
+// ControlPlaneJoin is a convenience wrapper around Join for joining a node as
+// a control plane member.
+func ControlPlaneJoin(ctx context.Context, ip string, controlPlaneConfigPath string, timeout time.Duration) error {
+	return Join(ctx, ip, RoleControlPlane, ConfigPaths{ControlPlaneConfigPath: controlPlaneConfigPath}, timeout)
+}
+
+const (
+	// bootstrapRetryInterval is how long Bootstrap waits between attempts
+	// while the node it just joined reboots.
+	bootstrapRetryInterval = 10 * time.Second
+
+	// bootstrapMaxWait bounds how long Bootstrap keeps retrying before giving
+	// up, covering the minutes ApplyConfiguration's default Mode=REBOOT takes
+	// to install to disk and come back up.
+	bootstrapMaxWait = 10 * time.Minute
+)
+
+// Bootstrap calls the Talos MachineService.Bootstrap RPC on the first
+// control plane node to initialize etcd for a brand new cluster. Join just
+// applied that node's machine config with the default Mode=REBOOT, so it
+// isn't reachable over its real certs again for several minutes; Bootstrap
+// retries with a fixed backoff, bounded by bootstrapMaxWait, instead of
+// giving up after a single attempt.
+func Bootstrap(ctx context.Context, ip string, talosConfigPath string, timeout time.Duration) error {
+	deadline := time.Now().Add(bootstrapMaxWait)
+
+	var lastErr error
+	for {
+		lastErr = bootstrapOnce(ctx, ip, talosConfigPath, timeout)
+		if lastErr == nil {
+			return nil
 		}
 
-		var node, namespace, _type, id, version, hostname, machineType, os string
-		unpack.Do(fields, &node, &namespace, &_type, &id, &version, &hostname, &machineType, &os)
-
-		member := Member{
-			Node:        fields[0],
-			Namespace:   namespace,
-			Type:        _type,
-			ID:          id,
-			Version:     version,
-			Hostname:    hostname,
-			MachineType: machineType,
-			OS:          os,
-			Addresses:   addresses,
-			InternalIP:  extractInternalIP(addresses),
+		if time.Now().After(deadline) {
+			return fmt.Errorf("failed to bootstrap %s after %s: %w", ip, bootstrapMaxWait, lastErr)
 		}
 
-		members = append(members, member)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(bootstrapRetryInterval):
+		}
 	}
-
-	return members, nil
 }
 
-func WorkerJoin(ctx context.Context, ip string, workerConfigPath string, talosConfigPath string, timeout time.Duration) error {
-	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
-
+// bootstrapOnce makes a single attempt to dial ip and call Bootstrap,
+// failing fast while the node is unreachable (e.g. mid-reboot).
+func bootstrapOnce(ctx context.Context, ip string, talosConfigPath string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	cmd := exec.CommandContext(cmdCtx, "talosctl", "apply-config", "--insecure", "--nodes", ip, "--file", workerConfigPath)
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, fmt.Sprintf("TALOSCONFIG=%s", talosConfigPath))
 
-	output, err := cmd.CombinedOutput()
+	c, err := client.New(ctx, client.WithConfigFromFile(talosConfigPath), client.WithEndpoints(ip))
 	if err != nil {
-		if cmdCtx.Err() == context.DeadlineExceeded {
-			return fmt.Errorf("command timed out after %v", timeout)
-		}
-		return fmt.Errorf("failed to join worker: %w, output: %s", err, string(output))
+		return fmt.Errorf("failed to create talos client: %w", err)
+	}
+	defer c.Close() //nolint:errcheck
+
+	if err := c.Bootstrap(ctx, &machine.BootstrapRequest{}); err != nil {
+		return fmt.Errorf("failed to bootstrap %s: %w", ip, err)
 	}
 
 	return nil
 }
+
+// selectInstallDisk picks an install disk from the node's maintenance-mode
+// block.Disk resources, skipping read-only disks and optical drives. It
+// returns "" if no suitable disk is found, leaving the machine config's own
+// install disk setting untouched.
+func selectInstallDisk(ctx context.Context, c *client.Client) string {
+	disks, err := safe.StateListAll[*block.Disk](ctx, c.COSI)
+	if err != nil {
+		return ""
+	}
+
+	for disk := range disks.All() {
+		spec := disk.TypedSpec()
+		if spec.Readonly || spec.CDROM {
+			continue
+		}
+
+		return spec.DevPath
+	}
+
+	return ""
+}
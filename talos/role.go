@@ -0,0 +1,64 @@
+package talos
+
+import "fmt"
+
+// Role selects which machine config a node should be joined with.
+type Role int
+
+const (
+	// RoleWorker joins a node as a worker.
+	RoleWorker Role = iota
+	// RoleControlPlane joins a node as a control plane member.
+	RoleControlPlane
+)
+
+// String implements fmt.Stringer.
+func (r Role) String() string {
+	switch r {
+	case RoleWorker:
+		return "worker"
+	case RoleControlPlane:
+		return "controlplane"
+	default:
+		return fmt.Sprintf("Role(%d)", r)
+	}
+}
+
+// ParseRole parses the CLI role names "worker" and "controlplane".
+func ParseRole(s string) (Role, error) {
+	switch s {
+	case "worker":
+		return RoleWorker, nil
+	case "controlplane":
+		return RoleControlPlane, nil
+	default:
+		return 0, fmt.Errorf("unknown role %q, expected \"worker\" or \"controlplane\"", s)
+	}
+}
+
+// ConfigPaths maps a Role to the machine config file that should be applied
+// for it.
+type ConfigPaths struct {
+	WorkerConfigPath       string
+	ControlPlaneConfigPath string
+}
+
+// forRole returns the config path for role, or an error if none is set.
+func (p ConfigPaths) forRole(role Role) (string, error) {
+	switch role {
+	case RoleWorker:
+		if p.WorkerConfigPath == "" {
+			return "", fmt.Errorf("no worker config path configured")
+		}
+
+		return p.WorkerConfigPath, nil
+	case RoleControlPlane:
+		if p.ControlPlaneConfigPath == "" {
+			return "", fmt.Errorf("no control plane config path configured")
+		}
+
+		return p.ControlPlaneConfigPath, nil
+	default:
+		return "", fmt.Errorf("unknown role %s", role)
+	}
+}